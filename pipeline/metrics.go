@@ -0,0 +1,27 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormatPrometheus renders a Metrics snapshot as Prometheus text
+// exposition format, labeling every series by stage name.
+func FormatPrometheus(stats map[string]Metrics) string {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		m := stats[name]
+		fmt.Fprintf(&b, "stage_inflight{stage=%q} %d\n", name, m.Inflight)
+		fmt.Fprintf(&b, "stage_queue_depth{stage=%q} %d\n", name, m.QueueDepth)
+		fmt.Fprintf(&b, "stage_process_duration_seconds{stage=%q} %f\n", name, m.ProcessDurationSeconds)
+		fmt.Fprintf(&b, "stage_errors_total{stage=%q} %d\n", name, m.ErrorsTotal)
+	}
+	return b.String()
+}
@@ -0,0 +1,489 @@
+// Package pipeline provides a generic, reusable multi-stage worker
+// framework: a chain of typed stages connected by buffered channels,
+// gated by a shared admission semaphore, with per-stage worker pools
+// and Prometheus-style metrics. It is the plumbing extracted from
+// rule_1's workCoordinator, which wires its three concrete stages
+// through it and layers retry, panic-supervision and autoscaling on
+// top as configuration.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrAtCapacity is returned by Admit when the semaphore could not be
+// acquired before timeout elapsed.
+var ErrAtCapacity = errors.New("pipeline: at capacity")
+
+// ErrBackedUp is returned by Feed when the first stage's input channel
+// could not accept the value before timeout elapsed.
+var ErrBackedUp = errors.New("pipeline: stage backed up")
+
+// Stage describes one step of a pipeline: how many workers process it,
+// how deep its input buffer is, and the function that turns IN into
+// OUT. Name labels its metrics and its worker goroutines.
+type Stage[IN, OUT any] struct {
+	Name       string
+	Workers    int
+	BufferSize int
+	Process    func(ctx context.Context, in IN) (OUT, error)
+
+	// Supervise, if set, wraps each worker's run loop (e.g. with panic
+	// recovery and respawn) instead of the framework starting it bare
+	// via `go run()`. It must return once run returns.
+	Supervise func(name string, run func())
+}
+
+// Metrics is one stage's Prometheus-style snapshot: stage_inflight,
+// stage_queue_depth, stage_process_duration_seconds (p95) and
+// stage_errors_total, all implicitly labeled by the stage's Name.
+type Metrics struct {
+	Inflight               int64
+	QueueDepth             int
+	ProcessDurationSeconds float64
+	ErrorsTotal            int64
+}
+
+// StageHandle lets an external controller (an autoscaler resizing a
+// pool, a supervisor restarting it after a panic) manage one stage's
+// worker count and read its metrics, without the controller needing to
+// know the stage's IN/OUT types.
+type StageHandle struct {
+	AddWorker    func()
+	RemoveWorker func() bool
+	Size         func() int
+	Metrics      func() Metrics
+
+	// BeginShutdown stops the stage from accepting new workers and
+	// arranges for it to close its output once its current workers have
+	// drained the stage's input and exited. Used by Pipeline.Quiesce.
+	BeginShutdown func()
+}
+
+// stageMetrics accumulates one stage's raw samples behind a mutex.
+type stageMetrics struct {
+	mu        sync.Mutex
+	inflight  int64
+	errors    int64
+	durations []time.Duration
+	next      int
+	filled    bool
+	queueLen  func() int
+}
+
+func newStageMetrics(window int) *stageMetrics {
+	if window <= 0 {
+		window = 200
+	}
+	return &stageMetrics{durations: make([]time.Duration, window)}
+}
+
+func (m *stageMetrics) incInflight(delta int64) {
+	m.mu.Lock()
+	m.inflight += delta
+	m.mu.Unlock()
+}
+
+func (m *stageMetrics) incErrors() {
+	m.mu.Lock()
+	m.errors++
+	m.mu.Unlock()
+}
+
+func (m *stageMetrics) recordDuration(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations[m.next] = d
+	m.next = (m.next + 1) % len(m.durations)
+	if m.next == 0 {
+		m.filled = true
+	}
+}
+
+func (m *stageMetrics) snapshot() Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.next
+	if m.filled {
+		n = len(m.durations)
+	}
+
+	var p95 time.Duration
+	if n > 0 {
+		sorted := append([]time.Duration(nil), m.durations[:n]...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		idx := int(float64(n) * 0.95)
+		if idx >= n {
+			idx = n - 1
+		}
+		p95 = sorted[idx]
+	}
+
+	queueDepth := 0
+	if m.queueLen != nil {
+		queueDepth = m.queueLen()
+	}
+
+	return Metrics{
+		Inflight:               m.inflight,
+		QueueDepth:             queueDepth,
+		ProcessDurationSeconds: p95.Seconds(),
+		ErrorsTotal:            m.errors,
+	}
+}
+
+// stageRuntime is the live, per-stage state: its input/output channels,
+// its dynamically sized worker pool, and its metrics. IN/OUT are erased
+// to `any` at the StageHandle boundary so a Builder can hold stages of
+// different types in one chain — Go does not allow a method to
+// introduce a type parameter of its own, so that erasure is also why
+// Builder.Then below is a free function rather than a fluent method.
+type stageRuntime[IN, OUT any] struct {
+	cfg Stage[IN, OUT]
+	in  <-chan IN
+	out chan OUT
+	ctx context.Context
+	sem chan struct{}
+	m   *stageMetrics
+
+	mu           sync.Mutex
+	workers      map[int]chan struct{}
+	nextID       int
+	shuttingDown bool
+	outClosed    bool
+}
+
+func newStageRuntime[IN, OUT any](ctx context.Context, sem chan struct{}, in <-chan IN, cfg Stage[IN, OUT]) *stageRuntime[IN, OUT] {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1
+	}
+
+	m := newStageMetrics(200)
+	m.queueLen = func() int { return len(in) }
+
+	r := &stageRuntime[IN, OUT]{
+		cfg:     cfg,
+		in:      in,
+		out:     make(chan OUT, cfg.BufferSize),
+		ctx:     ctx,
+		sem:     sem,
+		m:       m,
+		workers: make(map[int]chan struct{}),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		r.addWorker()
+	}
+
+	return r
+}
+
+func (r *stageRuntime[IN, OUT]) addWorker() {
+	id, quit, ok := r.register()
+	if !ok {
+		return
+	}
+
+	// run claims the id/quit addWorker just registered on its first call,
+	// so a caller like the autoscaler sees Size() reflect the new worker
+	// the instant AddWorker returns. Supervise (see the field doc above)
+	// may call this same closure again after a panic; a respawn re-
+	// registers a fresh id/quit instead of reusing the stale ones. If
+	// registration only happened once up front, a respawned worker would
+	// keep executing under the id its predecessor's workerDone already
+	// deleted: untracked by Size, unreachable by removeWorker, and
+	// invisible to beginShutdown's len(r.workers)==0 check, which would
+	// then close r.out out from under it.
+	spawned := false
+	run := func() {
+		workerID, workerQuit := id, quit
+		if spawned {
+			var ok bool
+			workerID, workerQuit, ok = r.register()
+			if !ok {
+				return
+			}
+		}
+		spawned = true
+
+		defer r.workerDone(workerID)
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-workerQuit:
+				return
+			case v, ok := <-r.in:
+				if !ok {
+					return
+				}
+				r.process(v)
+			}
+		}
+	}
+
+	if r.cfg.Supervise != nil {
+		go r.cfg.Supervise(r.cfg.Name, run)
+	} else {
+		go run()
+	}
+}
+
+// register adds a new worker to the pool and returns its id and quit
+// channel, or ok=false if the stage is shutting down and no new worker
+// should start (including a respawn after a panic).
+func (r *stageRuntime[IN, OUT]) register() (id int, quit chan struct{}, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.shuttingDown {
+		return 0, nil, false
+	}
+
+	id = r.nextID
+	r.nextID++
+	quit = make(chan struct{})
+	r.workers[id] = quit
+	return id, quit, true
+}
+
+// workerDone removes a finished worker from the pool and, if the stage
+// is shutting down and that was its last worker, closes out so the
+// next stage in the chain drains and closes in turn.
+func (r *stageRuntime[IN, OUT]) workerDone(id int) {
+	r.mu.Lock()
+	delete(r.workers, id)
+	closeOut := r.shuttingDown && len(r.workers) == 0 && !r.outClosed
+	if closeOut {
+		r.outClosed = true
+	}
+	r.mu.Unlock()
+
+	if closeOut {
+		close(r.out)
+	}
+}
+
+// beginShutdown stops the stage from accepting new workers. Once its
+// current workers have drained whatever is left in r.in and exited
+// (which happens once r.in is closed), the last one to exit closes
+// out via workerDone, cascading the shutdown to the next stage.
+func (r *stageRuntime[IN, OUT]) beginShutdown() {
+	r.mu.Lock()
+	r.shuttingDown = true
+	closeOut := len(r.workers) == 0 && !r.outClosed
+	if closeOut {
+		r.outClosed = true
+	}
+	r.mu.Unlock()
+
+	if closeOut {
+		close(r.out)
+	}
+}
+
+func (r *stageRuntime[IN, OUT]) removeWorker() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, quit := range r.workers {
+		close(quit)
+		delete(r.workers, id)
+		return true
+	}
+	return false
+}
+
+func (r *stageRuntime[IN, OUT]) size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.workers)
+}
+
+// process runs one item through the stage's Process func. On error it
+// only records the failure: whether a failed item is retried, dropped,
+// or dead-lettered, and whether the admission semaphore is released, is
+// the caller's business logic, not this framework's.
+func (r *stageRuntime[IN, OUT]) process(v IN) {
+	r.m.incInflight(1)
+	start := time.Now()
+	result, err := r.cfg.Process(r.ctx, v)
+	r.m.recordDuration(time.Since(start))
+	r.m.incInflight(-1)
+
+	if err != nil {
+		r.m.incErrors()
+		return
+	}
+
+	select {
+	case r.out <- result:
+	case <-r.ctx.Done():
+	}
+}
+
+func (r *stageRuntime[IN, OUT]) handle() *StageHandle {
+	return &StageHandle{
+		AddWorker:     r.addWorker,
+		RemoveWorker:  r.removeWorker,
+		Size:          r.size,
+		Metrics:       r.m.snapshot,
+		BeginShutdown: r.beginShutdown,
+	}
+}
+
+// Builder accumulates a chain of stages from an overall IN type to the
+// current OUT type. Go does not allow a method to introduce a type
+// parameter of its own, so the Then-as-a-method-on-Builder shape most
+// fluent pipeline DSLs use isn't directly expressible here; Then is a
+// free function instead, rebinding OUT on each call while still reading
+// as a composed chain: Build(Then(Then(New(sem, s1), s2), s3)).
+type Builder[IN, OUT any] struct {
+	sem  chan struct{}
+	wire func(ctx context.Context, in <-chan IN) (<-chan OUT, map[string]*StageHandle)
+}
+
+// New starts a Builder with sem as the pipeline's shared admission
+// semaphore and s as its first stage.
+func New[IN, OUT any](sem chan struct{}, s Stage[IN, OUT]) *Builder[IN, OUT] {
+	return &Builder[IN, OUT]{
+		sem: sem,
+		wire: func(ctx context.Context, in <-chan IN) (<-chan OUT, map[string]*StageHandle) {
+			rt := newStageRuntime[IN, OUT](ctx, sem, in, s)
+			return rt.out, map[string]*StageHandle{s.Name: rt.handle()}
+		},
+	}
+}
+
+// Then appends s to b, consuming b's current OUT type as s's IN.
+func Then[IN, MID, OUT any](b *Builder[IN, MID], s Stage[MID, OUT]) *Builder[IN, OUT] {
+	return &Builder[IN, OUT]{
+		sem: b.sem,
+		wire: func(ctx context.Context, in <-chan IN) (<-chan OUT, map[string]*StageHandle) {
+			mid, handles := b.wire(ctx, in)
+			rt := newStageRuntime[MID, OUT](ctx, b.sem, mid, s)
+			handles[s.Name] = rt.handle()
+			return rt.out, handles
+		},
+	}
+}
+
+// Build finalizes the chain into a Pipeline, ready for Start.
+func (b *Builder[IN, OUT]) Build() *Pipeline[IN, OUT] {
+	return &Pipeline[IN, OUT]{builder: b}
+}
+
+// Pipeline is a wired chain of stages from IN to OUT. It owns the
+// inter-stage channels and each stage's worker pool; admission-semaphore
+// acquisition and release stay explicit at the call sites (Admit,
+// Release, and whatever a Process func does on its own terminal paths),
+// matching how the rest of this package's caller already handles
+// backpressure and cleanup.
+type Pipeline[IN, OUT any] struct {
+	builder *Builder[IN, OUT]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	inCh   chan IN
+	outCh  <-chan OUT
+	stages map[string]*StageHandle
+}
+
+// Start wires every stage and begins running their configured initial
+// worker counts. inBuffer sizes the pipeline's entry channel, the one
+// buffer not owned by an individual stage.
+func (p *Pipeline[IN, OUT]) Start(ctx context.Context, inBuffer int) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.inCh = make(chan IN, inBuffer)
+
+	out, handles := p.builder.wire(p.ctx, p.inCh)
+	p.outCh = out
+	p.stages = handles
+}
+
+// Admit acquires one slot of the pipeline's shared semaphore, waiting up
+// to timeout.
+func (p *Pipeline[IN, OUT]) Admit(ctx context.Context, timeout time.Duration) error {
+	select {
+	case p.builder.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return ErrAtCapacity
+	}
+}
+
+// Release gives back a semaphore slot acquired via Admit. It is also
+// what a stage's Process func should call when it is itself the final
+// handler of a job's outcome (completion, cancellation, dead-lettering)
+// instead of letting the job flow to Drain.
+func (p *Pipeline[IN, OUT]) Release() { <-p.builder.sem }
+
+// Feed hands v to the first stage, waiting up to timeout (or
+// indefinitely if timeout <= 0). It does not touch the semaphore: pair
+// it with Admit/Release for a value that must acquire a fresh slot, or
+// call it alone to resubmit a value that already holds one, such as a
+// retried item.
+func (p *Pipeline[IN, OUT]) Feed(ctx context.Context, v IN, timeout time.Duration) error {
+	var after <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		after = t.C
+	}
+
+	select {
+	case p.inCh <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-after:
+		return ErrBackedUp
+	}
+}
+
+// Drain returns the channel of values that completed every stage. A
+// caller must keep consuming it (even if only to discard) or the final
+// stage's workers will block once its output buffer fills.
+func (p *Pipeline[IN, OUT]) Drain() <-chan OUT { return p.outCh }
+
+// Stage returns the named stage's handle, for external worker-count
+// control and metrics, or nil if no such stage was wired in.
+func (p *Pipeline[IN, OUT]) Stage(name string) *StageHandle { return p.stages[name] }
+
+// Metrics returns every stage's current Metrics snapshot, keyed by
+// Name.
+func (p *Pipeline[IN, OUT]) Metrics() map[string]Metrics {
+	out := make(map[string]Metrics, len(p.stages))
+	for name, h := range p.stages {
+		out[name] = h.Metrics()
+	}
+	return out
+}
+
+// Quiesce begins a graceful shutdown: it puts every stage into
+// shutdown mode (so none of them spin up new workers) and then closes
+// the entry channel. Each stage drains whatever is left in its input,
+// and its last worker to exit closes that stage's output, cascading
+// the close down the chain until Drain's channel closes too.
+//
+// The caller must have already guaranteed no further Feed calls will
+// be attempted - Quiesce does not itself close off admission, only
+// the channels it owns, and sending on inCh after it is closed panics.
+func (p *Pipeline[IN, OUT]) Quiesce() {
+	for _, h := range p.stages {
+		h.BeginShutdown()
+	}
+	close(p.inCh)
+}
+
+// Stop cancels the pipeline's context; workers exit once they next
+// check it or their quit channel.
+func (p *Pipeline[IN, OUT]) Stop() { p.cancel() }
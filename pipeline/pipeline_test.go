@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// slowStage returns a Stage that sleeps for delay before passing v
+// through unchanged, so its worker pool becomes the bottleneck that
+// exercises backpressure.
+func slowStage(name string, workers, buffer int, delay time.Duration) Stage[int, int] {
+	return Stage[int, int]{
+		Name:       name,
+		Workers:    workers,
+		BufferSize: buffer,
+		Process: func(ctx context.Context, in int) (int, error) {
+			time.Sleep(delay)
+			return in, nil
+		},
+	}
+}
+
+// TestFeedAppliesBackpressure drives synthetic jobs through a two-stage
+// chain whose single slow worker and 1-deep buffer can absorb at most
+// two items before a third Feed call should see ErrBackedUp.
+func TestFeedAppliesBackpressure(t *testing.T) {
+	sem := make(chan struct{}, 10) // admission isn't under test here
+	s1 := slowStage("slow", 1, 1, 50*time.Millisecond)
+	s2 := Stage[int, int]{Name: "passthrough", Workers: 1, BufferSize: 1, Process: func(ctx context.Context, in int) (int, error) {
+		return in, nil
+	}}
+
+	p := Then(New[int, int](sem, s1), s2).Build()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx, 1)
+
+	// The entry channel (inBuffer=1) plus the one worker it feeds can
+	// absorb two items without blocking; a third must wait for the
+	// worker to drain one.
+	if err := p.Feed(ctx, 1, 10*time.Millisecond); err != nil {
+		t.Fatalf("first Feed: unexpected backpressure: %v", err)
+	}
+	if err := p.Feed(ctx, 2, 10*time.Millisecond); err != nil {
+		t.Fatalf("second Feed: unexpected backpressure: %v", err)
+	}
+
+	if err := p.Feed(ctx, 3, 10*time.Millisecond); err != ErrBackedUp {
+		t.Fatalf("third Feed: want ErrBackedUp, got %v", err)
+	}
+
+	// Once the slow worker has had time to drain, the same item fits.
+	time.Sleep(100 * time.Millisecond)
+	if err := p.Feed(ctx, 3, 50*time.Millisecond); err != nil {
+		t.Fatalf("Feed after drain: unexpected backpressure: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-p.Drain():
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for item %d to complete", i)
+		}
+	}
+}
+
+// TestAdmitRespectsSemaphore checks that Admit enforces the shared
+// semaphore independently of stage backpressure.
+func TestAdmitRespectsSemaphore(t *testing.T) {
+	sem := make(chan struct{}, 1)
+	s := Stage[int, int]{Name: "noop", Workers: 1, BufferSize: 4, Process: func(ctx context.Context, in int) (int, error) {
+		return in, nil
+	}}
+
+	p := New[int, int](sem, s).Build()
+	ctx := context.Background()
+	p.Start(ctx, 4)
+
+	if err := p.Admit(ctx, 10*time.Millisecond); err != nil {
+		t.Fatalf("first Admit: unexpected error: %v", err)
+	}
+	if err := p.Admit(ctx, 10*time.Millisecond); err != ErrAtCapacity {
+		t.Fatalf("second Admit: want ErrAtCapacity, got %v", err)
+	}
+
+	p.Release()
+	if err := p.Admit(ctx, 10*time.Millisecond); err != nil {
+		t.Fatalf("Admit after Release: unexpected error: %v", err)
+	}
+}
+
+// TestStageHandleScaling exercises AddWorker/RemoveWorker/Size, the
+// hooks an external autoscaler drives.
+func TestStageHandleScaling(t *testing.T) {
+	sem := make(chan struct{}, 10)
+	s := slowStage("scaled", 0, 4, 10*time.Millisecond)
+
+	p := New[int, int](sem, s).Build()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx, 4)
+
+	h := p.Stage("scaled")
+	if h == nil {
+		t.Fatal("expected a handle for stage \"scaled\"")
+	}
+	if got := h.Size(); got != 0 {
+		t.Fatalf("initial Size() = %d, want 0", got)
+	}
+
+	h.AddWorker()
+	h.AddWorker()
+	if got := h.Size(); got != 2 {
+		t.Fatalf("Size() after two AddWorker = %d, want 2", got)
+	}
+
+	if ok := h.RemoveWorker(); !ok {
+		t.Fatal("RemoveWorker() = false, want true")
+	}
+	if got := h.Size(); got != 1 {
+		t.Fatalf("Size() after RemoveWorker = %d, want 1", got)
+	}
+}
+
+// TestSuperviseRespawnStaysTracked drives a worker through a panic and a
+// Supervise-triggered respawn, then checks that the respawned goroutine
+// re-registered itself: Size() must count it and RemoveWorker must be
+// able to target it. Before the fix, a respawn re-ran the same closure
+// under the id its predecessor's workerDone had already deleted, leaving
+// it permanently invisible to both.
+func TestSuperviseRespawnStaysTracked(t *testing.T) {
+	sem := make(chan struct{}, 10)
+	panicked := false
+	proceed := make(chan struct{})
+
+	s := Stage[int, int]{
+		Name:       "flaky",
+		Workers:    1,
+		BufferSize: 4,
+		Process: func(ctx context.Context, in int) (int, error) {
+			if !panicked {
+				panicked = true
+				panic("boom")
+			}
+			<-proceed
+			return in, nil
+		},
+		// A minimal respawning Supervise, standing in for
+		// supervisor.runSupervised: recover and re-run fn once.
+		Supervise: func(name string, run func()) {
+			func() {
+				defer func() { recover() }()
+				run()
+			}()
+			run()
+		},
+	}
+
+	p := New[int, int](sem, s).Build()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx, 4)
+
+	if err := p.Feed(ctx, 1, 50*time.Millisecond); err != nil {
+		t.Fatalf("feed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	h := p.Stage("flaky")
+	if got := h.Size(); got != 1 {
+		t.Fatalf("Size() after panic+respawn = %d, want 1 (respawned worker untracked)", got)
+	}
+	if ok := h.RemoveWorker(); !ok {
+		t.Fatal("RemoveWorker() = false, want true (respawned worker should be reachable)")
+	}
+
+	close(proceed)
+}
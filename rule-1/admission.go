@@ -0,0 +1,222 @@
+package rule_1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+// tenantAdmissionDeadline bounds how long a tenant's rate limiter and
+// concurrency semaphore are given to admit a job, matching the fast-
+// reject deadline SubmitJob already uses for the global semaphore.
+const tenantAdmissionDeadline = 100 * time.Millisecond
+
+// TenantMetrics is a point-in-time view of one tenant's admission state.
+type TenantMetrics struct {
+	Rejections     int64
+	ConcurrencyCap int64
+}
+
+// tenantBucket holds one tenant's rate limiter and in-flight cap.
+type tenantBucket struct {
+	limiter *rate.Limiter
+
+	// sem enforces the per-tenant in-flight cap. It is sized at
+	// maxConcurrent once, at creation, and never replaced: recompute
+	// narrows a tenant's effective cap by acquiring the unused headroom
+	// for itself (and widens it by releasing that headroom back), rather
+	// than swapping in a freshly sized instance. A swap would orphan
+	// slots a job already holds against the old instance, letting the
+	// tenant run at old-cap+new-cap concurrently until those drain; the
+	// single long-lived instance can't be over-subscribed that way, and
+	// narrowing only takes effect once enough outstanding slots are
+	// released to free the headroom it needs.
+	sem *semaphore.Weighted
+	cap int64
+
+	lastUsed   time.Time
+	rejections int64
+}
+
+// fairShareResizeTimeout bounds how long recompute waits to reserve the
+// extra headroom a cap reduction needs. If outstanding jobs haven't
+// released enough slots in time, the resize is left for the next tick
+// rather than blocking the recompute loop indefinitely.
+const fairShareResizeTimeout = 2 * time.Second
+
+// tenantAdmission rate-limits and concurrency-caps jobs per tenant, so
+// one noisy tenant can't starve the rest of workSemaphore. Each tenant
+// gets its own token-bucket limiter (rps+burst) and, since
+// maxConcurrent/activeTenants, a fair share of in-flight slots.
+type tenantAdmission struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantBucket
+
+	rps   rate.Limit
+	burst int
+
+	maxConcurrent int64
+	idleTTL       time.Duration
+}
+
+func newTenantAdmission(rps rate.Limit, burst int, maxConcurrent int64, idleTTL time.Duration) *tenantAdmission {
+	return &tenantAdmission{
+		tenants:       make(map[string]*tenantBucket),
+		rps:           rps,
+		burst:         burst,
+		maxConcurrent: maxConcurrent,
+		idleTTL:       idleTTL,
+	}
+}
+
+// admit blocks briefly on the tenant's rate limiter and concurrency
+// semaphore. On success it returns the semaphore instance the slot was
+// acquired from; the caller must Release(1) on it exactly once when the
+// job finishes. Returning the instance (rather than a release closure)
+// keeps job, which carries this value, a comparable type.
+func (a *tenantAdmission) admit(ctx context.Context, tenantID string) (*semaphore.Weighted, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, tenantAdmissionDeadline)
+	defer cancel()
+
+	b := a.getOrCreate(tenantID)
+
+	if err := b.limiter.Wait(waitCtx); err != nil {
+		a.recordRejection(tenantID)
+		return nil, fmt.Errorf("tenant %q rate-limited: %w", tenantID, err)
+	}
+
+	a.mu.Lock()
+	sem := b.sem
+	a.mu.Unlock()
+
+	if err := sem.Acquire(waitCtx, 1); err != nil {
+		a.recordRejection(tenantID)
+		return nil, fmt.Errorf("tenant %q at concurrency cap: %w", tenantID, err)
+	}
+
+	return sem, nil
+}
+
+func (a *tenantAdmission) getOrCreate(tenantID string) *tenantBucket {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b, ok := a.tenants[tenantID]
+	if !ok {
+		b = &tenantBucket{
+			limiter: rate.NewLimiter(a.rps, a.burst),
+			sem:     semaphore.NewWeighted(a.maxConcurrent),
+			cap:     a.maxConcurrent,
+		}
+		a.tenants[tenantID] = b
+	}
+	b.lastUsed = time.Now()
+	return b
+}
+
+func (a *tenantAdmission) recordRejection(tenantID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if b, ok := a.tenants[tenantID]; ok {
+		b.rejections++
+	}
+}
+
+// recompute evicts tenants idle for longer than idleTTL and gives each
+// remaining tenant a fair share of maxConcurrent, resizing its effective
+// cap when that share changes.
+func (a *tenantAdmission) recompute() {
+	a.mu.Lock()
+	now := time.Now()
+	for id, b := range a.tenants {
+		if now.Sub(b.lastUsed) > a.idleTTL {
+			delete(a.tenants, id)
+		}
+	}
+
+	if len(a.tenants) == 0 {
+		a.mu.Unlock()
+		return
+	}
+
+	fairShare := a.maxConcurrent / int64(len(a.tenants))
+	if fairShare < 1 {
+		fairShare = 1
+	}
+
+	buckets := make([]*tenantBucket, 0, len(a.tenants))
+	for _, b := range a.tenants {
+		buckets = append(buckets, b)
+	}
+	a.mu.Unlock()
+
+	// Resizing happens outside a.mu so a shrink (which may have to wait
+	// for outstanding slots to drain) never blocks other tenants' admits.
+	for _, b := range buckets {
+		a.resizeBucket(b, fairShare)
+	}
+}
+
+// resizeBucket moves bucket b's effective cap toward target by
+// acquiring or releasing the delta against its long-lived semaphore.
+// Growing releases headroom back immediately; shrinking acquires the
+// extra headroom it needs, which only succeeds once enough of the
+// tenant's outstanding slots have been released. If a shrink can't
+// acquire that headroom within fairShareResizeTimeout, b.cap is left
+// unchanged and the same delta is retried on the next recompute tick.
+func (a *tenantAdmission) resizeBucket(b *tenantBucket, target int64) {
+	a.mu.Lock()
+	delta := target - b.cap
+	sem := b.sem
+	a.mu.Unlock()
+
+	switch {
+	case delta == 0:
+		return
+	case delta > 0:
+		sem.Release(delta)
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), fairShareResizeTimeout)
+		defer cancel()
+		if err := sem.Acquire(ctx, -delta); err != nil {
+			return
+		}
+	}
+
+	a.mu.Lock()
+	b.cap = target
+	a.mu.Unlock()
+}
+
+// runRecompute periodically re-balances per-tenant caps and evicts idle
+// tenants, until stopC is closed.
+func (a *tenantAdmission) runRecompute(stopC <-chan struct{}) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopC:
+			return
+		case <-ticker.C:
+			a.recompute()
+		}
+	}
+}
+
+// Snapshot returns a per-tenant view of rejection counts and current
+// concurrency caps, for GetMetrics/health reporting.
+func (a *tenantAdmission) Snapshot() map[string]TenantMetrics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make(map[string]TenantMetrics, len(a.tenants))
+	for id, b := range a.tenants {
+		out[id] = TenantMetrics{Rejections: b.rejections, ConcurrencyCap: b.cap}
+	}
+	return out
+}
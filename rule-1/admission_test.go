@@ -0,0 +1,181 @@
+package rule_1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+// TestTenantAdmissionRecomputeFairShare checks that recompute divides
+// maxConcurrent evenly across active tenants and resizes each tenant's
+// semaphore when its share changes.
+func TestTenantAdmissionRecomputeFairShare(t *testing.T) {
+	a := newTenantAdmission(rate.Inf, 100, 10, time.Hour)
+
+	ctx := context.Background()
+	if _, err := a.admit(ctx, "t1"); err != nil {
+		t.Fatalf("admit t1: %v", err)
+	}
+	if _, err := a.admit(ctx, "t2"); err != nil {
+		t.Fatalf("admit t2: %v", err)
+	}
+
+	a.recompute()
+
+	snap := a.Snapshot()
+	if got := snap["t1"].ConcurrencyCap; got != 5 {
+		t.Fatalf("t1 cap = %d, want 5 (10/2 tenants)", got)
+	}
+	if got := snap["t2"].ConcurrencyCap; got != 5 {
+		t.Fatalf("t2 cap = %d, want 5 (10/2 tenants)", got)
+	}
+
+	if _, err := a.admit(ctx, "t3"); err != nil {
+		t.Fatalf("admit t3: %v", err)
+	}
+	a.recompute()
+
+	snap = a.Snapshot()
+	if got := snap["t1"].ConcurrencyCap; got != 3 {
+		t.Fatalf("t1 cap after 3rd tenant = %d, want 3 (10/3, floor)", got)
+	}
+}
+
+// TestTenantAdmissionRecomputeEvictsIdleTenants checks that a tenant idle
+// longer than idleTTL is forgotten, so its state doesn't linger forever.
+func TestTenantAdmissionRecomputeEvictsIdleTenants(t *testing.T) {
+	a := newTenantAdmission(rate.Inf, 100, 10, time.Millisecond)
+
+	ctx := context.Background()
+	if _, err := a.admit(ctx, "stale"); err != nil {
+		t.Fatalf("admit: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	a.recompute()
+
+	if _, ok := a.Snapshot()["stale"]; ok {
+		t.Fatal("tenant \"stale\" still present after exceeding idleTTL, want evicted")
+	}
+}
+
+// TestTenantAdmissionRecomputeNoRaceOnSem drives concurrent admit/release
+// against a tenant while recompute runs on another goroutine, under
+// -race: admit reads the bucket's semaphore while recompute resizes it,
+// and before the fix recompute replaced the semaphore pointer itself
+// outside any lock held by admit, which -race reliably caught.
+func TestTenantAdmissionRecomputeNoRaceOnSem(t *testing.T) {
+	a := newTenantAdmission(rate.Inf, 100, 10, time.Hour)
+	ctx := context.Background()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if sem, err := a.admit(ctx, "t1"); err == nil {
+				sem.Release(1)
+			}
+			if sem, err := a.admit(ctx, fmt.Sprintf("t%d", i%5)); err == nil {
+				sem.Release(1)
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			a.recompute()
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+}
+
+// TestTenantAdmissionRecomputeShrinkWaitsForOutstanding checks that
+// narrowing a tenant's cap doesn't let it exceed the new cap while a job
+// acquired under the old, larger cap is still outstanding: the resize
+// only takes effect once that job releases.
+func TestTenantAdmissionRecomputeShrinkWaitsForOutstanding(t *testing.T) {
+	a := newTenantAdmission(rate.Inf, 100, 10, time.Hour)
+	ctx := context.Background()
+
+	if _, err := a.admit(ctx, "t1"); err != nil {
+		t.Fatalf("admit t1 (1st): %v", err)
+	}
+	sem2, err := a.admit(ctx, "t1")
+	if err != nil {
+		t.Fatalf("admit t1 (2nd): %v", err)
+	}
+	sem3, err := a.admit(ctx, "t1")
+	if err != nil {
+		t.Fatalf("admit t1 (3rd): %v", err)
+	}
+
+	// A 2nd tenant joins, driving t1's fair share down to 5; t1 is still
+	// holding only 3 slots, below the new cap, so the shrink must
+	// complete immediately without needing anything released.
+	if _, err := a.admit(ctx, "t2"); err != nil {
+		t.Fatalf("admit t2: %v", err)
+	}
+	a.recompute()
+
+	if got := a.Snapshot()["t1"].ConcurrencyCap; got != 5 {
+		t.Fatalf("t1 cap = %d, want 5", got)
+	}
+
+	// t1 already holds 3 of its new cap of 5; it can reach the cap but
+	// no further, even though the underlying semaphore was never
+	// swapped out from under it.
+	held := []*semaphore.Weighted{}
+	for i := 0; i < 2; i++ {
+		sem, err := a.admit(ctx, "t1")
+		if err != nil {
+			t.Fatalf("admit t1 (slot %d of 5): %v", i, err)
+		}
+		held = append(held, sem)
+	}
+	if _, err := a.admit(ctx, "t1"); err == nil {
+		t.Fatal("admit t1 beyond new cap of 5 = nil error, want rejection")
+	}
+
+	for _, sem := range held {
+		sem.Release(1)
+	}
+	sem2.Release(1)
+	sem3.Release(1)
+}
+
+// TestTenantAdmissionConcurrencyCapRejects checks that a tenant at its
+// concurrency cap is rejected rather than admitted, and that the
+// rejection is recorded.
+func TestTenantAdmissionConcurrencyCapRejects(t *testing.T) {
+	a := newTenantAdmission(rate.Inf, 100, 1, time.Hour)
+
+	ctx := context.Background()
+	if _, err := a.admit(ctx, "t1"); err != nil {
+		t.Fatalf("1st admit: %v", err)
+	}
+
+	if _, err := a.admit(ctx, "t1"); err == nil {
+		t.Fatal("2nd admit at cap = nil error, want rejection")
+	}
+
+	if got := a.Snapshot()["t1"].Rejections; got != 1 {
+		t.Fatalf("Rejections = %d, want 1", got)
+	}
+}
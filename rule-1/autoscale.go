@@ -0,0 +1,184 @@
+package rule_1
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ercross/concurrent-program-rules/pipeline"
+)
+
+// stageScaler replaces a hard-coded worker count with a control loop
+// that grows or shrinks a stage's worker pool to track load. It samples
+// occupancy (queue depth/buffer size) once a second: two consecutive
+// samples above 0.8 add a worker, two consecutive samples below 0.2
+// remove one via the stage's handle. It also applies Little's Law
+// (desired = ceil(arrival_rate * p95 service time)) so a latency spike
+// grows the pool even before occupancy alone would. Worker bookkeeping
+// and the p95 service-time estimate both live in the pipeline package's
+// StageHandle now; the scaler only owns the hysteresis/Little's-Law
+// control loop on top of it.
+type stageScaler struct {
+	name       string
+	min, max   int
+	bufferSize int
+
+	handle   *pipeline.StageHandle
+	arrivals *rateTracker
+
+	mu          sync.Mutex
+	aboveStreak int
+	belowStreak int
+
+	stopC chan struct{}
+	doneC chan struct{}
+}
+
+func newStageScaler(name string, min, max, bufferSize int, handle *pipeline.StageHandle) *stageScaler {
+	return &stageScaler{
+		name:       name,
+		min:        min,
+		max:        max,
+		bufferSize: bufferSize,
+		handle:     handle,
+		arrivals:   newRateTracker(),
+		stopC:      make(chan struct{}),
+		doneC:      make(chan struct{}),
+	}
+}
+
+// start tops the worker pool up to min and begins the ~1s control loop.
+func (s *stageScaler) start() {
+	s.ensureMin()
+	go s.run()
+}
+
+func (s *stageScaler) run() {
+	defer close(s.doneC)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopC:
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+func (s *stageScaler) sample() {
+	m := s.handle.Metrics()
+	occ := float64(m.QueueDepth) / float64(s.bufferSize)
+	arrivalRate := s.arrivals.rate()
+	serviceTime := time.Duration(m.ProcessDurationSeconds * float64(time.Second))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.handle.Size()
+	target := current
+
+	switch {
+	case occ > 0.8:
+		s.aboveStreak++
+		s.belowStreak = 0
+		if s.aboveStreak >= 2 {
+			target = current + 1
+			s.aboveStreak = 0
+		}
+	case occ < 0.2:
+		s.belowStreak++
+		s.aboveStreak = 0
+		if s.belowStreak >= 2 {
+			target = current - 1
+			s.belowStreak = 0
+		}
+	default:
+		s.aboveStreak = 0
+		s.belowStreak = 0
+	}
+
+	if arrivalRate > 0 && serviceTime > 0 {
+		desired := int(math.Ceil(arrivalRate * serviceTime.Seconds()))
+		if desired > target {
+			target = desired
+		}
+	}
+
+	target = clampInt(target, s.min, s.max)
+
+	for s.handle.Size() < target {
+		s.handle.AddWorker()
+	}
+	for s.handle.Size() > target {
+		if !s.handle.RemoveWorker() {
+			break
+		}
+	}
+}
+
+// size reports the current worker count for this stage.
+func (s *stageScaler) size() int {
+	return s.handle.Size()
+}
+
+// ensureMin tops the pool back up to min, used both to seed the initial
+// pool at start and after an operator-driven Restart of a stage whose
+// workers all panicked away.
+func (s *stageScaler) ensureMin() {
+	for s.handle.Size() < s.min {
+		s.handle.AddWorker()
+	}
+}
+
+func (s *stageScaler) stop() {
+	close(s.stopC)
+	<-s.doneC
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// rateTracker measures events/sec over the interval since it was last
+// queried, resetting on each read. It is meant to be sampled once per
+// control-loop tick.
+type rateTracker struct {
+	mu    sync.Mutex
+	count int64
+	since time.Time
+}
+
+func newRateTracker() *rateTracker {
+	return &rateTracker{since: time.Now()}
+}
+
+func (r *rateTracker) mark() {
+	r.mu.Lock()
+	r.count++
+	r.mu.Unlock()
+}
+
+func (r *rateTracker) rate() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := time.Since(r.since).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+
+	rate := float64(r.count) / elapsed
+	r.count = 0
+	r.since = time.Now()
+	return rate
+}
@@ -0,0 +1,123 @@
+package rule_1
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ercross/concurrent-program-rules/pipeline"
+)
+
+// fakeStageHandle is a minimal, in-memory stand-in for a pipeline
+// StageHandle, so stageScaler's hysteresis/Little's-Law logic can be
+// exercised without wiring up a real pipeline.
+type fakeStageHandle struct {
+	mu      sync.Mutex
+	size    int
+	metrics pipeline.Metrics
+}
+
+func (f *fakeStageHandle) handle() *pipeline.StageHandle {
+	return &pipeline.StageHandle{
+		AddWorker: func() {
+			f.mu.Lock()
+			f.size++
+			f.mu.Unlock()
+		},
+		RemoveWorker: func() bool {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if f.size == 0 {
+				return false
+			}
+			f.size--
+			return true
+		},
+		Size: func() int {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			return f.size
+		},
+		Metrics: func() pipeline.Metrics {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			return f.metrics
+		},
+	}
+}
+
+func (f *fakeStageHandle) setMetrics(m pipeline.Metrics) {
+	f.mu.Lock()
+	f.metrics = m
+	f.mu.Unlock()
+}
+
+// TestStageScalerGrowsOnSustainedHighOccupancy checks that two consecutive
+// samples above the 0.8 occupancy threshold add one worker, and that a
+// single high sample alone does not.
+func TestStageScalerGrowsOnSustainedHighOccupancy(t *testing.T) {
+	f := &fakeStageHandle{}
+	s := newStageScaler("stage", 1, 5, 10, f.handle())
+	s.ensureMin()
+
+	f.setMetrics(pipeline.Metrics{QueueDepth: 9}) // occ = 0.9 > 0.8
+	s.sample()
+	if got := s.size(); got != 1 {
+		t.Fatalf("size after 1st high sample = %d, want 1 (still within streak)", got)
+	}
+
+	s.sample()
+	if got := s.size(); got != 2 {
+		t.Fatalf("size after 2nd high sample = %d, want 2", got)
+	}
+}
+
+// TestStageScalerShrinksOnSustainedLowOccupancy checks that two
+// consecutive samples below the 0.2 occupancy threshold remove one
+// worker, bounded by min.
+func TestStageScalerShrinksOnSustainedLowOccupancy(t *testing.T) {
+	f := &fakeStageHandle{}
+	s := newStageScaler("stage", 1, 5, 10, f.handle())
+	s.ensureMin()
+	f.handle().AddWorker() // size=2, above min so there's room to shrink
+
+	f.setMetrics(pipeline.Metrics{QueueDepth: 1}) // occ = 0.1 < 0.2
+	s.sample()
+	if got := s.size(); got != 2 {
+		t.Fatalf("size after 1st low sample = %d, want 2 (still within streak)", got)
+	}
+
+	s.sample()
+	if got := s.size(); got != 1 {
+		t.Fatalf("size after 2nd low sample = %d, want 1", got)
+	}
+
+	// Further low samples must not shrink below min.
+	s.sample()
+	s.sample()
+	if got := s.size(); got != 1 {
+		t.Fatalf("size after shrinking past min = %d, want still 1 (min)", got)
+	}
+}
+
+// TestStageScalerLittlesLawGrowsAheadOfOccupancy checks that a latency
+// spike grows the pool via Little's Law even when occupancy alone
+// wouldn't yet justify it.
+func TestStageScalerLittlesLawGrowsAheadOfOccupancy(t *testing.T) {
+	f := &fakeStageHandle{}
+	s := newStageScaler("stage", 1, 10, 10, f.handle())
+	s.ensureMin()
+
+	s.arrivals.count = 5 // newRateTracker seeds `since`; fake 5 arrivals/sec
+	s.arrivals.since = time.Now().Add(-time.Second)
+
+	f.setMetrics(pipeline.Metrics{
+		QueueDepth:             0, // occ = 0, well under the 0.8 threshold
+		ProcessDurationSeconds: 1, // 5 arrivals/sec * 1s service time = 5
+	})
+	s.sample()
+
+	if got := s.size(); got != 5 {
+		t.Fatalf("size after Little's-Law sample = %d, want 5", got)
+	}
+}
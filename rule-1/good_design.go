@@ -2,13 +2,20 @@ package rule_1
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
+
+	"github.com/ercross/concurrent-program-rules/pipeline"
+	"golang.org/x/sync/semaphore"
 )
 
 // job represents a unit of work
@@ -17,6 +24,14 @@ type job struct {
 	Data      string
 	CreatedAt time.Time
 	ctx       context.Context
+
+	tenantID string
+	// tenantSem is the per-tenant semaphore instance this job acquired a
+	// slot from; releasing against the same instance it acquired from
+	// stays correct even if recompute has since resized the tenant's
+	// current semaphore. A pointer field keeps job comparable, which the
+	// retry WorkQueue[job] requires; a func field would not be.
+	tenantSem *semaphore.Weighted
 }
 
 // stage1Result represents intermediate processing result
@@ -25,23 +40,43 @@ type stage1Result struct {
 	Processed string
 	Stage     int
 	CreatedAt time.Time
+
+	// origJob is carried through stage2/stage3 so a failure at any stage
+	// can requeue the same job that started the pipeline.
+	origJob job
 }
 
+// errTransient marks an error as safe to retry through the retry queue,
+// as opposed to a permanent failure that should be surfaced immediately.
+var errTransient = errors.New("transient processing error")
+
+// errDropped marks a job a stage intentionally did not forward (it was
+// cancelled, or the stage already released it directly) rather than
+// failed; it must never be routed through the retry queue.
+var errDropped = errors.New("job dropped")
+
+// ErrShuttingDown is returned by SubmitJob once Stop has begun: admission
+// is closed before the pipeline itself starts draining, so callers get a
+// fast, unambiguous rejection instead of racing the shutdown.
+var ErrShuttingDown = errors.New("coordinator is shutting down")
+
 // workCoordinator owns the entire flow of work
 // It has a clear capacity limit and enforces backpressure
 type workCoordinator struct {
-	// Fixed-size semaphore controls max concurrent work
+	// Fixed-size semaphore controls max concurrent work. It is shared
+	// with pipe as its admission semaphore; Admit/Release/Feed on pipe
+	// are the only things that touch it once Start runs.
 	workSemaphore chan struct{}
 
-	// Worker pools with fixed sizes
-	stage1Workers int
-	stage2Workers int
-	stage3Workers int
+	// pipe wires the three stages below into a running chain, owning
+	// their channels and worker pools.
+	pipe *pipeline.Pipeline[job, stage1Result]
 
-	// Channels sized to match worker capacity (small buffers)
-	stage1Chan chan job
-	stage2Chan chan stage1Result
-	stage3Chan chan stage1Result
+	// Worker pools autoscale between min and max based on each stage's
+	// queue depth and p95 processing latency, instead of a fixed count.
+	stage1Scaler *stageScaler
+	stage2Scaler *stageScaler
+	stage3Scaler *stageScaler
 
 	// Metrics
 	mu            sync.Mutex
@@ -50,69 +85,346 @@ type workCoordinator struct {
 	completedJobs int64
 	totalLatency  time.Duration
 
+	// retryQueue holds jobs that failed at any stage with a transient
+	// error, so they survive downstream flakiness instead of being
+	// dropped. Jobs that exceed maxRetries go to deadLetters.
+	retryQueue *WorkQueue[job]
+
+	// supervisor restarts stage workers that panic and trips a circuit
+	// breaker, recorded in degradedStages, if a stage panics too often.
+	supervisor     *supervisor
+	degradedStages map[string]bool
+
+	// tenantAdmission rate-limits and caps in-flight jobs per tenant so
+	// admission is fair before workSemaphore is ever touched.
+	tenantAdmission *tenantAdmission
+
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// Graceful-shutdown state. admissionClosed is closed the moment Stop
+	// is called, so SubmitJob and retryDispatcher can reject/skip new
+	// work immediately; producers tracks every goroutine that observed
+	// admissionClosed still open and is mid-Feed, so Stop can wait for
+	// them to finish before it closes the pipeline's entry channel.
+	admissionClosed chan struct{}
+	producers       sync.WaitGroup
+	waitC           chan struct{}
+
+	stopMu   sync.Mutex
+	stopping bool
+	ready    bool
 }
 
 func newWorkCoordinator(maxConcurrent int) *workCoordinator {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	return &workCoordinator{
+	wc := &workCoordinator{
 		// Semaphore enforces global concurrency limit
 		workSemaphore: make(chan struct{}, maxConcurrent),
 
-		// Worker counts define capacity
-		stage1Workers: 5,
-		stage2Workers: 3,
-		stage3Workers: 2,
+		degradedStages:  make(map[string]bool),
+		admissionClosed: make(chan struct{}),
+		waitC:           make(chan struct{}),
+	}
 
-		// Small buffers - just enough to smooth flow
-		// Not to hide backpressure
-		stage1Chan: make(chan job, 5),
-		stage2Chan: make(chan stage1Result, 3),
-		stage3Chan: make(chan stage1Result, 2),
+	wc.retryQueue = NewWorkQueue[job](WorkQueueConfig[job]{
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		GlobalRPS:   50,
+		GlobalBurst: 10,
+		MaxRetries:  5,
+		DeadLetter:  wc,
+	})
+
+	// Allow each stage up to 3 panics within 10s before giving up on
+	// respawning its workers and flipping the coordinator into degraded.
+	wc.supervisor = newSupervisor(3, 10*time.Second, wc.markDegraded)
+
+	// Each tenant gets 10rps/burst 20, a fair share of maxConcurrent
+	// in-flight, and is forgotten after 2 minutes of inactivity.
+	wc.tenantAdmission = newTenantAdmission(10, 20, int64(maxConcurrent), 2*time.Minute)
+
+	// Buffer sizes (5, 3, 2) match the original fixed channel sizes;
+	// worker counts start at 0 and are brought up to each scaler's min
+	// once pipe.Start runs.
+	supervise := func(name string, run func()) { wc.supervisor.runSupervised(name, run) }
+	builder := pipeline.Then(
+		pipeline.Then(
+			pipeline.New[job, stage1Result](wc.workSemaphore, pipeline.Stage[job, stage1Result]{
+				Name: "stage1", BufferSize: 5, Process: wc.processStage1, Supervise: supervise,
+			}),
+			pipeline.Stage[stage1Result, stage1Result]{
+				Name: "stage2", BufferSize: 3, Process: wc.processStage2, Supervise: supervise,
+			},
+		),
+		pipeline.Stage[stage1Result, stage1Result]{
+			Name: "stage3", BufferSize: 2, Process: wc.processStage3, Supervise: supervise,
+		},
+	)
+	wc.pipe = builder.Build()
+
+	return wc
+}
 
-		ctx:    ctx,
-		cancel: cancel,
+// scalerFor returns the stageScaler for a stage name, or nil if
+// unknown.
+func (wc *workCoordinator) scalerFor(stage string) *stageScaler {
+	switch stage {
+	case "stage1":
+		return wc.stage1Scaler
+	case "stage2":
+		return wc.stage2Scaler
+	case "stage3":
+		return wc.stage3Scaler
+	default:
+		return nil
 	}
 }
 
-func (wc *workCoordinator) Start() {
-	// Start fixed worker pools
-	for i := 0; i < wc.stage1Workers; i++ {
-		go wc.stage1Worker(i)
-	}
+// SetDeadLetterSink replaces the default dead-letter handling for jobs
+// that exhaust their retry budget. Must be called before Start.
+func (wc *workCoordinator) SetDeadLetterSink(sink DeadLetterSink[job]) {
+	wc.retryQueue.dead = sink
+}
 
-	for i := 0; i < wc.stage2Workers; i++ {
-		go wc.stage2Worker(i)
+func (wc *workCoordinator) Start(ctx context.Context) error {
+	wc.ctx, wc.cancel = context.WithCancel(ctx)
+
+	// Wire the pipeline's channels and bring each stage up with 0
+	// workers; the scalers below top them up to their configured min.
+	wc.pipe.Start(wc.ctx, 5)
+
+	// min/max bracket the original fixed worker counts (2, 1, 1 min) so
+	// a fresh coordinator behaves similarly until load pushes it either
+	// way. Buffer sizes mirror each stage's BufferSize above.
+	wc.stage1Scaler = newStageScaler("stage1", 2, 10, 5, wc.pipe.Stage("stage1"))
+	wc.stage2Scaler = newStageScaler("stage2", 1, 6, 3, wc.pipe.Stage("stage2"))
+	wc.stage3Scaler = newStageScaler("stage3", 1, 4, 2, wc.pipe.Stage("stage3"))
+
+	// Start each stage's autoscaling worker pool at its configured
+	// minimum; the control loop grows or shrinks it from there.
+	wc.stage1Scaler.start()
+	wc.stage2Scaler.start()
+	wc.stage3Scaler.start()
+
+	// Jobs that complete stage3 are handled entirely inside
+	// processStage3 (semaphore release, metrics); this just keeps
+	// Drain's channel from filling and blocking stage3's workers.
+	go func() {
+		for range wc.pipe.Drain() {
+		}
+	}()
+
+	// Re-submits jobs the retry queue has released, either because their
+	// AddAfter delay elapsed or AddRateLimited's backoff expired.
+	go wc.supervisor.runSupervised("retry-dispatcher", wc.retryDispatcher)
+
+	// Re-balances per-tenant concurrency caps and evicts idle tenants.
+	go wc.supervisor.runSupervised("tenant-admission", func() {
+		wc.tenantAdmission.runRecompute(wc.ctx.Done())
+	})
+
+	wc.stopMu.Lock()
+	wc.ready = true
+	wc.stopMu.Unlock()
+
+	log.Printf("Started coordinator: max_concurrent=%d, initial_workers=[%d,%d,%d]",
+		cap(wc.workSemaphore), wc.stage1Scaler.size(), wc.stage2Scaler.size(), wc.stage3Scaler.size())
+	return nil
+}
+
+// StageSizes reports the current worker count per stage, driven by each
+// stage's autoscaler rather than a fixed value.
+func (wc *workCoordinator) StageSizes() map[string]int {
+	return map[string]int{
+		"stage1": wc.stage1Scaler.size(),
+		"stage2": wc.stage2Scaler.size(),
+		"stage3": wc.stage3Scaler.size(),
 	}
+}
+
+// Send implements DeadLetterSink[job]: it is the coordinator's default
+// handling for a job that exhausted its retries. Since the job will
+// never reach stage3, its semaphore slot is released here instead.
+func (wc *workCoordinator) Send(j job, err error) {
+	log.Printf("job %d dead-lettered after exhausting retries: %v", j.ID, err)
+	wc.pipe.Release()
+	j.tenantSem.Release(1)
+	wc.trackJobEnd(time.Since(j.CreatedAt), false)
+}
 
-	for i := 0; i < wc.stage3Workers; i++ {
-		go wc.stage3Worker(i)
+// markDegraded is called by the supervisor when a stage's circuit
+// breaker trips. HealthCheck reports 503 while any stage is degraded.
+func (wc *workCoordinator) markDegraded(stage string) {
+	wc.mu.Lock()
+	wc.degradedStages[stage] = true
+	wc.mu.Unlock()
+	log.Printf("coordinator degraded: %s stopped respawning after repeated panics", stage)
+}
+
+// Degraded reports whether any stage has tripped its circuit breaker.
+func (wc *workCoordinator) Degraded() bool {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	return len(wc.degradedStages) > 0
+}
+
+// Restart clears a degraded stage's circuit breaker and tops its
+// worker pool back up to the configured minimum. It is the operator's
+// recovery path after investigating and fixing whatever was causing
+// that stage to panic.
+func (wc *workCoordinator) Restart(stage string) error {
+	scaler := wc.scalerFor(stage)
+	if scaler == nil {
+		return fmt.Errorf("unknown stage %q", stage)
 	}
 
-	log.Printf("Started coordinator: max_concurrent=%d, workers=[%d,%d,%d]",
-		cap(wc.workSemaphore), wc.stage1Workers, wc.stage2Workers, wc.stage3Workers)
+	wc.mu.Lock()
+	delete(wc.degradedStages, stage)
+	wc.mu.Unlock()
+	wc.supervisor.reset(stage)
+	scaler.ensureMin()
+
+	log.Printf("coordinator: %s restarted by operator", stage)
+	return nil
 }
 
-func (wc *workCoordinator) Stop() {
+// retryDispatcher feeds jobs released by the retry queue back into stage1,
+// the same entry point fresh jobs use. It calls Feed rather than Admit
+// first: a retried job already holds the semaphore slot it acquired
+// when it was first submitted.
+func (wc *workCoordinator) retryDispatcher() {
+	for {
+		j, shutdown := wc.retryQueue.Get()
+		if shutdown {
+			return
+		}
+
+		select {
+		case <-wc.admissionClosed:
+			// Admission is closed and the pipeline's entry channel is
+			// about to (or already did) close; feeding it now would
+			// panic. Drop the job - it counts as abandoned, not lost
+			// silently, since its semaphore slot is simply never
+			// released and Stop's drain wait will time out on it.
+			wc.retryQueue.Done(j)
+			continue
+		default:
+		}
+
+		wc.producers.Add(1)
+		err := wc.pipe.Feed(wc.ctx, j, 0)
+		wc.producers.Done()
+		if err != nil {
+			wc.retryQueue.Done(j)
+			return
+		}
+		wc.retryQueue.Done(j)
+	}
+}
+
+// Stop begins a graceful shutdown: admission is closed immediately, then
+// the pipeline is quiesced (each stage drains and closes in turn), then
+// Stop waits for every admitted job to release its workSemaphore slot -
+// up to ctx's deadline, after which any job still holding one is
+// abandoned rather than waited on forever. Wait() unblocks once this
+// returns.
+func (wc *workCoordinator) Stop(ctx context.Context) error {
+	wc.stopMu.Lock()
+	if wc.stopping {
+		wc.stopMu.Unlock()
+		<-wc.waitC
+		return nil
+	}
+	wc.stopping = true
+	wc.ready = false
+	wc.stopMu.Unlock()
+
+	close(wc.admissionClosed)
+	wc.producers.Wait()
+
+	wc.stage1Scaler.stop()
+	wc.stage2Scaler.stop()
+	wc.stage3Scaler.stop()
+
+	wc.pipe.Quiesce()
+
+	drained := wc.waitForDrain(ctx)
+
+	wc.retryQueue.ShutDown()
 	wc.cancel()
+
+	active, completed, rejected, _ := wc.GetMetrics()
+	if drained {
+		log.Printf("coordinator stopped cleanly: completed=%d rejected=%d", completed, rejected)
+	} else {
+		log.Printf("coordinator stopped with %d job(s) abandoned mid-flight: completed=%d rejected=%d", active, completed, rejected)
+	}
+
+	close(wc.waitC)
+	return nil
+}
+
+// waitForDrain polls until every admitted job has released its
+// workSemaphore slot, or ctx expires first.
+func (wc *workCoordinator) waitForDrain(ctx context.Context) bool {
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if len(wc.workSemaphore) == 0 {
+			return true
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
 }
 
-// SubmitJob enforces backpressure at admission
-// If system is at capacity, it rejects immediately
-func (wc *workCoordinator) SubmitJob(ctx context.Context, data string) error {
-	// Try to acquire semaphore - this is the backpressure point
+// Wait returns a channel closed once Stop has finished draining and
+// returned.
+func (wc *workCoordinator) Wait() <-chan struct{} {
+	return wc.waitC
+}
+
+// Ready reports whether the coordinator is accepting new jobs. It goes
+// false the moment Stop begins, before the drain itself completes, so a
+// readiness probe can stop routing traffic immediately.
+func (wc *workCoordinator) Ready() bool {
+	wc.stopMu.Lock()
+	defer wc.stopMu.Unlock()
+	return wc.ready
+}
+
+// SubmitJob enforces backpressure at admission: a per-tenant token
+// bucket and in-flight cap first, then the pipeline's shared semaphore.
+// If either gate is exhausted, it rejects immediately rather than
+// accumulating goroutines.
+func (wc *workCoordinator) SubmitJob(ctx context.Context, tenantID, data string) error {
 	select {
-	case wc.workSemaphore <- struct{}{}:
-		// Got permission to proceed
-	case <-ctx.Done():
+	case <-wc.admissionClosed:
+		return ErrShuttingDown
+	default:
+	}
+	wc.producers.Add(1)
+	defer wc.producers.Done()
+
+	tenantSem, err := wc.tenantAdmission.admit(ctx, tenantID)
+	if err != nil {
 		wc.recordRejection()
-		return errors.New("request cancelled")
-	case <-time.After(100 * time.Millisecond):
-		// Fast rejection instead of accumulating goroutines
+		return err
+	}
+
+	// Try to acquire the pipeline's semaphore - this is the backpressure
+	// point.
+	if err := wc.pipe.Admit(ctx, 100*time.Millisecond); err != nil {
+		tenantSem.Release(1)
 		wc.recordRejection()
-		return errors.New("system at capacity - try again later")
+		if errors.Is(err, pipeline.ErrAtCapacity) {
+			return errors.New("system at capacity - try again later")
+		}
+		return errors.New("request cancelled")
 	}
 
 	job := job{
@@ -120,106 +432,121 @@ func (wc *workCoordinator) SubmitJob(ctx context.Context, data string) error {
 		Data:      data,
 		CreatedAt: time.Now(),
 		ctx:       ctx,
+		tenantID:  tenantID,
+		tenantSem: tenantSem,
 	}
 
 	wc.trackJobStart()
 
-	// Submit to pipeline with timeout
-	// If stage1 is backed up, we find out quickly
-	select {
-	case wc.stage1Chan <- job:
-		return nil
-	case <-time.After(1 * time.Second):
-		// Release semaphore - we couldn't submit
-		<-wc.workSemaphore
-		wc.trackJobEnd(time.Since(job.CreatedAt), false)
-		return errors.New("stage1 backed up - system degraded")
-	case <-ctx.Done():
-		<-wc.workSemaphore
+	// Feed into stage1 with a timeout - if it's backed up, we find out
+	// quickly rather than blocking the caller.
+	if err := wc.pipe.Feed(ctx, job, 1*time.Second); err != nil {
+		wc.pipe.Release()
+		tenantSem.Release(1)
 		wc.trackJobEnd(time.Since(job.CreatedAt), false)
+		if errors.Is(err, pipeline.ErrBackedUp) {
+			return errors.New("stage1 backed up - system degraded")
+		}
 		return errors.New("request cancelled")
 	}
+
+	return nil
 }
 
-func (wc *workCoordinator) stage1Worker(id int) {
-	for {
-		select {
-		case <-wc.ctx.Done():
-			return
-		case job := <-wc.stage1Chan:
-			wc.processStage1(job)
+// processStage1 is stage1's pipeline.Stage.Process func. The pipeline
+// framework owns the worker loop, channel send, and backpressure; this
+// just does the work and decides what happens to a job that fails:
+// retry it, or forward it as the stage's result.
+//
+// The panic-recovery wrapper routes the in-flight job to the retry
+// queue before re-panicking, so the job survives the worker this
+// panic's Supervise hook is about to tear down and respawn.
+func (wc *workCoordinator) processStage1(ctx context.Context, job job) (result stage1Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			wc.retryQueue.Retry(job, fmt.Errorf("%w: panic in stage1: %v", errTransient, r))
+			panic(r)
 		}
-	}
-}
+	}()
+
+	wc.stage1Scaler.arrivals.mark()
 
-func (wc *workCoordinator) processStage1(job job) {
 	// Simulate processing
 	time.Sleep(time.Duration(50+rand.Intn(50)) * time.Millisecond)
 
-	result := stage1Result{
-		JobID:     job.ID,
-		Processed: fmt.Sprintf("stage1-%s", job.Data),
-		Stage:     1,
-		CreatedAt: job.CreatedAt,
+	if rand.Intn(20) == 0 {
+		err = fmt.Errorf("%w: stage1 failed for job %d", errTransient, job.ID)
+		wc.retryQueue.Retry(job, err)
+		return stage1Result{}, err
 	}
 
-	// Blocking send - if stage2 is slow, we wait
-	// This naturally creates backpressure up the chain
 	select {
-	case wc.stage2Chan <- result:
-		// Sent successfully
 	case <-job.ctx.Done():
 		// job cancelled, release semaphore
-		<-wc.workSemaphore
+		wc.pipe.Release()
+		job.tenantSem.Release(1)
 		wc.trackJobEnd(time.Since(job.CreatedAt), false)
-	case <-wc.ctx.Done():
-		return
+		wc.retryQueue.Forget(job)
+		return stage1Result{}, errDropped
+	default:
 	}
+
+	return stage1Result{
+		JobID:     job.ID,
+		Processed: fmt.Sprintf("stage1-%s", job.Data),
+		Stage:     1,
+		CreatedAt: job.CreatedAt,
+		origJob:   job,
+	}, nil
 }
 
-func (wc *workCoordinator) stage2Worker(id int) {
-	for {
-		select {
-		case <-wc.ctx.Done():
-			return
-		case result := <-wc.stage2Chan:
-			wc.processStage2(result)
+func (wc *workCoordinator) processStage2(ctx context.Context, result stage1Result) (out stage1Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			wc.retryQueue.Retry(result.origJob, fmt.Errorf("%w: panic in stage2: %v", errTransient, r))
+			panic(r)
 		}
-	}
-}
+	}()
+
+	wc.stage2Scaler.arrivals.mark()
 
-func (wc *workCoordinator) processStage2(result stage1Result) {
 	// Simulate processing
 	time.Sleep(time.Duration(100+rand.Intn(100)) * time.Millisecond)
 
+	if rand.Intn(20) == 0 {
+		err = fmt.Errorf("%w: stage2 failed for job %d", errTransient, result.JobID)
+		wc.retryQueue.Retry(result.origJob, err)
+		return stage1Result{}, err
+	}
+
 	result.Processed = fmt.Sprintf("stage2-%s", result.Processed)
 	result.Stage = 2
-
-	select {
-	case wc.stage3Chan <- result:
-		// Sent successfully
-	case <-wc.ctx.Done():
-		return
-	}
+	return result, nil
 }
 
-func (wc *workCoordinator) stage3Worker(id int) {
-	for {
-		select {
-		case <-wc.ctx.Done():
-			return
-		case result := <-wc.stage3Chan:
-			wc.processStage3(result)
+func (wc *workCoordinator) processStage3(ctx context.Context, result stage1Result) (out stage1Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			wc.retryQueue.Retry(result.origJob, fmt.Errorf("%w: panic in stage3: %v", errTransient, r))
+			panic(r)
 		}
-	}
-}
+	}()
+
+	wc.stage3Scaler.arrivals.mark()
 
-func (wc *workCoordinator) processStage3(result stage1Result) {
 	// Intentionally slow - the bottleneck
 	time.Sleep(time.Duration(200+rand.Intn(300)) * time.Millisecond)
 
+	if rand.Intn(20) == 0 {
+		err = fmt.Errorf("%w: stage3 failed for job %d", errTransient, result.JobID)
+		wc.retryQueue.Retry(result.origJob, err)
+		return stage1Result{}, err
+	}
+
 	// job complete - release semaphore
-	<-wc.workSemaphore
+	wc.pipe.Release()
+	result.origJob.tenantSem.Release(1)
+	wc.retryQueue.Forget(result.origJob)
 
 	latency := time.Since(result.CreatedAt)
 	wc.trackJobEnd(latency, true)
@@ -227,6 +554,8 @@ func (wc *workCoordinator) processStage3(result stage1Result) {
 	if latency > 2*time.Second {
 		log.Printf("job %d completed in %v (slow but controlled)", result.JobID, latency)
 	}
+
+	return result, nil
 }
 
 func (wc *workCoordinator) trackJobStart() {
@@ -266,6 +595,13 @@ func (wc *workCoordinator) GetMetrics() (active int, completed int64, rejected i
 	return
 }
 
+// TenantMetrics reports each known tenant's rejection count and current
+// fair-share concurrency cap, so operators can see who is being
+// throttled and why.
+func (wc *workCoordinator) TenantMetrics() map[string]TenantMetrics {
+	return wc.tenantAdmission.Snapshot()
+}
+
 // FixedService uses the coordinator to enforce backpressure
 type FixedService struct {
 	coordinator *workCoordinator
@@ -277,16 +613,17 @@ func NewFixedService() *FixedService {
 	}
 }
 
-func (s *FixedService) Start() {
-	s.coordinator.Start()
-}
-
 func (s *FixedService) HandleRequest(w http.ResponseWriter, r *http.Request) {
 	// Submit with timeout context
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	err := s.coordinator.SubmitJob(ctx, fmt.Sprintf("request-%d", rand.Int()))
+	tenantID := r.Header.Get("X-Tenant-ID")
+	if tenantID == "" {
+		tenantID = "anonymous"
+	}
+
+	err := s.coordinator.SubmitJob(ctx, tenantID, fmt.Sprintf("request-%d", rand.Int()))
 
 	if err != nil {
 		// System pushed back - return 503
@@ -299,31 +636,63 @@ func (s *FixedService) HandleRequest(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "job accepted")
 }
 
+// ScaleStatus reports per-stage worker counts via /scale, so operators
+// can watch the autoscaler track load instead of it being fixed at
+// construction time.
+func (s *FixedService) ScaleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.coordinator.StageSizes())
+}
+
+// PipelineMetrics serves the pipeline package's per-stage metrics
+// (stage_inflight, stage_queue_depth, stage_process_duration_seconds,
+// stage_errors_total) in Prometheus text exposition format via /metrics.
+func (s *FixedService) PipelineMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprint(w, pipeline.FormatPrometheus(s.coordinator.pipe.Metrics()))
+}
+
+// HealthCheck serves both readiness and liveness from one endpoint:
+// a 503 here means "stop routing new traffic", not "the process is
+// dead" - it fires the same way whether a stage is degraded or the
+// coordinator is mid-shutdown, while the process itself keeps running
+// and draining either way.
 func (s *FixedService) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	active, completed, rejected, avgLatency := s.coordinator.GetMetrics()
 
-	// Health check reflects actual system state
 	status := http.StatusOK
-	if rejected > completed {
+	if rejected > completed || s.coordinator.Degraded() || !s.coordinator.Ready() {
 		status = http.StatusServiceUnavailable
 	}
 
 	w.WriteHeader(status)
-	fmt.Fprintf(w, "active=%d completed=%d rejected=%d avg_latency=%v",
-		active, completed, rejected, avgLatency)
+	fmt.Fprintf(w, "active=%d completed=%d rejected=%d avg_latency=%v degraded=%v ready=%v tenants=%v",
+		active, completed, rejected, avgLatency, s.coordinator.Degraded(), s.coordinator.Ready(), s.coordinator.TenantMetrics())
 }
 
 func RunGoodDesign() {
 	service := NewFixedService()
-	service.Start()
+	if err := service.Start(context.Background()); err != nil {
+		log.Fatalf("failed to start service: %v", err)
+	}
 
-	http.HandleFunc("/job", service.HandleRequest)
-	http.HandleFunc("/health", service.HealthCheck)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/job", service.HandleRequest)
+	mux.HandleFunc("/health", service.HealthCheck)
+	mux.HandleFunc("/scale", service.ScaleStatus)
+	mux.HandleFunc("/metrics", service.PipelineMetrics)
+	server := &http.Server{Addr: ":8080", Handler: mux}
 
 	log.Println("Fixed service starting on :8080")
 	log.Println("System enforces backpressure - will reject when at capacity")
 	log.Println("No unbounded goroutine growth or hidden queue buildup")
 
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("http server error: %v", err)
+		}
+	}()
+
 	// Simulate load
 	go func() {
 		time.Sleep(2 * time.Second)
@@ -354,5 +723,21 @@ func RunGoodDesign() {
 		}
 	}()
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, syscall.SIGTERM, syscall.SIGINT)
+	<-sigC
+	log.Println("shutdown signal received, draining in-flight jobs...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("http server shutdown: %v", err)
+	}
+	if err := service.Stop(shutdownCtx); err != nil {
+		log.Printf("service shutdown: %v", err)
+	}
+	<-service.Wait()
+
+	log.Println("shutdown complete")
 }
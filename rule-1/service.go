@@ -0,0 +1,34 @@
+package rule_1
+
+import "context"
+
+// Service is the lifecycle every long-running component in this package
+// implements: start it, stop it gracefully within ctx's deadline,
+// observe when it has actually finished, and whether it is currently
+// ready to take traffic. workCoordinator and FixedService both satisfy
+// it; RunGoodDesign drives it from an http.Server and a SIGTERM handler.
+type Service interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Wait() <-chan struct{}
+	Ready() bool
+}
+
+var _ Service = (*FixedService)(nil)
+var _ Service = (*workCoordinator)(nil)
+
+func (s *FixedService) Start(ctx context.Context) error {
+	return s.coordinator.Start(ctx)
+}
+
+func (s *FixedService) Stop(ctx context.Context) error {
+	return s.coordinator.Stop(ctx)
+}
+
+func (s *FixedService) Wait() <-chan struct{} {
+	return s.coordinator.Wait()
+}
+
+func (s *FixedService) Ready() bool {
+	return s.coordinator.Ready()
+}
@@ -0,0 +1,125 @@
+package rule_1
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWorkCoordinatorLifecycleDrainsCleanly drives a workCoordinator
+// through its full Service lifecycle - Start, one submitted job left to
+// finish on its own, then Stop - and checks that Stop leaves no job
+// active and Wait's channel closed. The job is allowed to clear the
+// pipeline (including any simulated per-stage retry) before Stop is
+// called: calling Stop while a retry is still in flight races
+// SubmitJob's own documented abandon-on-shutdown behavior, which is
+// exercised separately and isn't what this test is checking.
+func TestWorkCoordinatorLifecycleDrainsCleanly(t *testing.T) {
+	wc := newWorkCoordinator(5)
+	if err := wc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !wc.Ready() {
+		t.Fatal("Ready() = false right after Start, want true")
+	}
+
+	if err := wc.SubmitJob(context.Background(), "tenant", "payload"); err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+
+	deadline := time.After(15 * time.Second)
+	for {
+		if active, _, _, _ := wc.GetMetrics(); active == 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("job never reached a terminal state before the poll deadline")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wc.Stop(stopCtx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	select {
+	case <-wc.Wait():
+	default:
+		t.Fatal("Wait() channel not closed immediately after Stop returns")
+	}
+
+	if wc.Ready() {
+		t.Fatal("Ready() = true after Stop, want false")
+	}
+
+	active, _, _, _ := wc.GetMetrics()
+	if active != 0 {
+		t.Fatalf("active jobs after clean drain = %d, want 0", active)
+	}
+}
+
+// TestWorkCoordinatorReadyGoesFalseImmediately checks that Ready flips to
+// false as soon as Stop begins, rather than only once the drain finishes -
+// so a readiness probe racing with shutdown stops routing traffic right
+// away instead of racing the in-flight drain.
+func TestWorkCoordinatorReadyGoesFalseImmediately(t *testing.T) {
+	wc := newWorkCoordinator(5)
+	if err := wc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if err := wc.SubmitJob(context.Background(), "tenant", "payload"); err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stopDone := make(chan struct{})
+	go func() {
+		wc.Stop(stopCtx)
+		close(stopDone)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if wc.Ready() {
+		t.Fatal("Ready() = true shortly after Stop began, want false")
+	}
+
+	select {
+	case <-stopDone:
+	case <-time.After(35 * time.Second):
+		t.Fatal("Stop never returned")
+	}
+}
+
+// TestWorkCoordinatorStopIsIdempotent checks that calling Stop again while
+// a first Stop is still draining waits for the same shutdown instead of
+// racing it or erroring.
+func TestWorkCoordinatorStopIsIdempotent(t *testing.T) {
+	wc := newWorkCoordinator(5)
+	if err := wc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	done := make(chan error, 2)
+	go func() { done <- wc.Stop(ctx) }()
+	go func() { done <- wc.Stop(ctx) }()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Stop returned error: %v", err)
+			}
+		case <-time.After(35 * time.Second):
+			t.Fatal("concurrent Stop calls never both returned")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package rule_1
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// supervisor restarts long-running worker goroutines after a panic,
+// instead of letting the panic permanently shrink the worker pool. It
+// tracks panics per name (typically a pipeline stage) and trips a
+// circuit breaker, stopping respawns, once a name panics too often
+// within a sliding window.
+type supervisor struct {
+	mu     sync.Mutex
+	panics map[string]int64 // panics_total{stage="..."}
+	recent map[string][]time.Time
+	open   map[string]bool
+
+	maxPanics int
+	window    time.Duration
+	onTrip    func(name string)
+}
+
+func newSupervisor(maxPanics int, window time.Duration, onTrip func(name string)) *supervisor {
+	return &supervisor{
+		panics:    make(map[string]int64),
+		recent:    make(map[string][]time.Time),
+		open:      make(map[string]bool),
+		maxPanics: maxPanics,
+		window:    window,
+		onTrip:    onTrip,
+	}
+}
+
+// runSupervised runs fn, recovering and respawning it if it panics. It
+// returns once fn returns normally (the usual shutdown path, e.g. a
+// worker observing ctx.Done) or once the circuit breaker for name is
+// open. This is the wrapper every long-running goroutine in the package
+// should be started through.
+func (s *supervisor) runSupervised(name string, fn func()) {
+	for {
+		if s.circuitOpen(name) {
+			log.Printf("supervisor: %s circuit open, not respawning", name)
+			return
+		}
+
+		if panicked := s.runOnce(name, fn); !panicked {
+			return
+		}
+
+		log.Printf("supervisor: respawning %s after panic", name)
+	}
+}
+
+func (s *supervisor) runOnce(name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			log.Printf("supervisor: panic in %s: %v\n%s", name, r, debug.Stack())
+			s.recordPanic(name)
+		}
+	}()
+
+	fn()
+	return false
+}
+
+func (s *supervisor) recordPanic(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.panics[name]++
+
+	now := time.Now()
+	cutoff := now.Add(-s.window)
+	kept := s.recent[name][:0]
+	for _, t := range s.recent[name] {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	kept = append(kept, now)
+	s.recent[name] = kept
+
+	if len(kept) >= s.maxPanics && !s.open[name] {
+		s.open[name] = true
+		if s.onTrip != nil {
+			s.onTrip(name)
+		}
+	}
+}
+
+func (s *supervisor) circuitOpen(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.open[name]
+}
+
+// reset clears the circuit breaker and panic history for name, allowing
+// runSupervised to respawn workers for it again.
+func (s *supervisor) reset(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.open, name)
+	delete(s.recent, name)
+}
+
+// PanicsTotal returns a snapshot of panics_total, labeled by name.
+func (s *supervisor) PanicsTotal() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]int64, len(s.panics))
+	for k, v := range s.panics {
+		out[k] = v
+	}
+	return out
+}
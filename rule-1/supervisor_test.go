@@ -0,0 +1,115 @@
+package rule_1
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRunSupervisedRespawnsAfterPanic checks that a panicking fn is
+// recovered and re-run, rather than runSupervised returning, as long as
+// the circuit breaker hasn't tripped.
+func TestRunSupervisedRespawnsAfterPanic(t *testing.T) {
+	s := newSupervisor(10, time.Second, nil)
+
+	var calls int32
+	done := make(chan struct{})
+	fn := func() {
+		n := atomic.AddInt32(&calls, 1)
+		if n < 3 {
+			panic("boom")
+		}
+		close(done)
+	}
+
+	go s.runSupervised("worker", fn)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for runSupervised to respawn after panics")
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("calls = %d, want 3 (2 panics + 1 clean run)", got)
+	}
+	if got := s.PanicsTotal()["worker"]; got != 2 {
+		t.Fatalf("PanicsTotal()[\"worker\"] = %d, want 2", got)
+	}
+}
+
+// TestSupervisorCircuitBreakerTrips checks that once a name panics
+// maxPanics times within window, the circuit opens, onTrip fires exactly
+// once, and runSupervised stops respawning it.
+func TestSupervisorCircuitBreakerTrips(t *testing.T) {
+	var tripped int32
+	var trippedName string
+	s := newSupervisor(3, time.Minute, func(name string) {
+		atomic.AddInt32(&tripped, 1)
+		trippedName = name
+	})
+
+	var calls int32
+	fn := func() {
+		atomic.AddInt32(&calls, 1)
+		panic("boom")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.runSupervised("flaky", fn)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for runSupervised to give up after circuit trips")
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("calls = %d, want 3 (maxPanics)", got)
+	}
+	if got := atomic.LoadInt32(&tripped); got != 1 {
+		t.Fatalf("onTrip called %d times, want 1", got)
+	}
+	if trippedName != "flaky" {
+		t.Fatalf("onTrip name = %q, want \"flaky\"", trippedName)
+	}
+	if !s.circuitOpen("flaky") {
+		t.Fatal("circuitOpen(\"flaky\") = false, want true")
+	}
+
+	// Further calls must not respawn at all.
+	s.runSupervised("flaky", fn)
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("calls after circuit open = %d, want still 3", got)
+	}
+}
+
+// TestSupervisorResetReopensCircuit checks that reset clears the circuit
+// breaker and panic history so runSupervised will respawn the name again.
+func TestSupervisorResetReopensCircuit(t *testing.T) {
+	s := newSupervisor(1, time.Minute, nil)
+
+	s.runSupervised("flaky", func() { panic("boom") })
+	if !s.circuitOpen("flaky") {
+		t.Fatal("circuitOpen(\"flaky\") = false after tripping, want true")
+	}
+
+	s.reset("flaky")
+	if s.circuitOpen("flaky") {
+		t.Fatal("circuitOpen(\"flaky\") = true after reset, want false")
+	}
+
+	var calls int32
+	done := make(chan struct{})
+	s.runSupervised("flaky", func() {
+		atomic.AddInt32(&calls, 1)
+		close(done)
+	})
+	<-done
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls after reset = %d, want 1", got)
+	}
+}
@@ -0,0 +1,374 @@
+package rule_1
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DeadLetterSink receives items that have exhausted their retry budget.
+// Implementations might log, persist to a database, or forward to a
+// separate queue for manual inspection.
+type DeadLetterSink[T any] interface {
+	Send(item T, err error)
+}
+
+// logDeadLetterSink is the default sink used when none is configured.
+type logDeadLetterSink[T any] struct{}
+
+func (logDeadLetterSink[T]) Send(item T, err error) {
+	log.Printf("dead-lettering item %v after exhausting retries: %v", item, err)
+}
+
+// delayedItem is an item waiting for its ready-at time to elapse before
+// it is moved into the ready FIFO.
+type delayedItem[T any] struct {
+	item    T
+	readyAt time.Time
+	index   int
+}
+
+// delayedHeap is a container/heap.Interface min-heap ordered by readyAt.
+type delayedHeap[T any] []*delayedItem[T]
+
+func (h delayedHeap[T]) Len() int           { return len(h) }
+func (h delayedHeap[T]) Less(i, j int) bool { return h[i].readyAt.Before(h[j].readyAt) }
+func (h delayedHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *delayedHeap[T]) Push(x any) {
+	item := x.(*delayedItem[T])
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *delayedHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// backoffLimiter tracks per-item exponential backoff state: delay grows
+// as base*2^failures, capped at max.
+type backoffLimiter[T comparable] struct {
+	mu       sync.Mutex
+	failures map[T]int
+	base     time.Duration
+	max      time.Duration
+}
+
+func newBackoffLimiter[T comparable](base, max time.Duration) *backoffLimiter[T] {
+	return &backoffLimiter[T]{
+		failures: make(map[T]int),
+		base:     base,
+		max:      max,
+	}
+}
+
+// when returns the delay to apply for this item's next attempt and
+// records the attempt.
+func (b *backoffLimiter[T]) when(item T) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	exp := b.failures[item]
+	b.failures[item] = exp + 1
+
+	delay := b.base * time.Duration(uint64(1)<<uint(minInt(exp, 32)))
+	if delay <= 0 || delay > b.max {
+		delay = b.max
+	}
+	return delay
+}
+
+func (b *backoffLimiter[T]) numRequeues(item T) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures[item]
+}
+
+func (b *backoffLimiter[T]) forget(item T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, item)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// WorkQueue is a reconciliation-style queue: items can be added, deferred
+// to a future time, or rate-limited after a failure, and at most one
+// in-flight copy of any given item is ever handed out via Get. It is
+// generic over the stage input type so it can sit in front of any of the
+// pipeline stages.
+type WorkQueue[T comparable] struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	ready      []T        // FIFO of items ready to be handed out
+	dirty      map[T]bool // items that need to be (re)delivered
+	processing map[T]bool // items currently checked out via Get
+
+	shuttingDown bool
+
+	waiting    delayedHeap[T]
+	wakeC      chan struct{}
+	stopC      chan struct{}
+	dispatched sync.WaitGroup
+
+	backoff *backoffLimiter[T]
+	global  *rate.Limiter
+
+	maxRetries int
+	dead       DeadLetterSink[T]
+}
+
+// WorkQueueConfig configures the retry and rate-limiting behaviour of a
+// WorkQueue.
+type WorkQueueConfig[T comparable] struct {
+	BaseDelay   time.Duration // starting backoff delay, e.g. 50ms
+	MaxDelay    time.Duration // backoff ceiling, e.g. 30s
+	GlobalRPS   rate.Limit    // overall dispatch rate across all items
+	GlobalBurst int           // burst allowance for GlobalRPS
+	MaxRetries  int           // attempts before an item is dead-lettered
+	DeadLetter  DeadLetterSink[T]
+}
+
+// NewWorkQueue builds a WorkQueue and starts its delay dispatcher goroutine.
+// Callers should call Close when the queue is no longer needed to stop
+// that goroutine.
+func NewWorkQueue[T comparable](cfg WorkQueueConfig[T]) *WorkQueue[T] {
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 50 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 30 * time.Second
+	}
+	if cfg.GlobalRPS <= 0 {
+		cfg.GlobalRPS = rate.Inf
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.DeadLetter == nil {
+		cfg.DeadLetter = logDeadLetterSink[T]{}
+	}
+
+	q := &WorkQueue[T]{
+		dirty:      make(map[T]bool),
+		processing: make(map[T]bool),
+		wakeC:      make(chan struct{}, 1),
+		stopC:      make(chan struct{}),
+		backoff:    newBackoffLimiter[T](cfg.BaseDelay, cfg.MaxDelay),
+		global:     rate.NewLimiter(cfg.GlobalRPS, maxOf(cfg.GlobalBurst, 1)),
+		maxRetries: cfg.MaxRetries,
+		dead:       cfg.DeadLetter,
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	q.dispatched.Add(1)
+	go q.runDispatcher()
+
+	return q
+}
+
+func maxOf(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Add places item directly onto the ready FIFO, deferring delivery until
+// Done is called if the item is already being processed.
+func (q *WorkQueue[T]) Add(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.shuttingDown {
+		return
+	}
+	if q.dirty[item] {
+		return
+	}
+	q.dirty[item] = true
+	if q.processing[item] {
+		// Will be re-added to the FIFO once Done is called.
+		return
+	}
+
+	q.ready = append(q.ready, item)
+	q.cond.Signal()
+}
+
+// AddAfter schedules item to be added to the queue once delay elapses.
+func (q *WorkQueue[T]) AddAfter(item T, delay time.Duration) {
+	if delay <= 0 {
+		q.Add(item)
+		return
+	}
+
+	q.mu.Lock()
+	if q.shuttingDown {
+		q.mu.Unlock()
+		return
+	}
+	heap.Push(&q.waiting, &delayedItem[T]{item: item, readyAt: time.Now().Add(delay)})
+	q.mu.Unlock()
+
+	select {
+	case q.wakeC <- struct{}{}:
+	default:
+	}
+}
+
+// AddRateLimited schedules item using the per-item exponential backoff
+// delay (base*2^failures, capped at max).
+func (q *WorkQueue[T]) AddRateLimited(item T) {
+	q.AddAfter(item, q.backoff.when(item))
+}
+
+// Retry is the glue used by stage workers: on a transient error it
+// requeues item via AddRateLimited, unless it has already exceeded
+// maxRetries, in which case it is forgotten and handed to the
+// DeadLetterSink.
+func (q *WorkQueue[T]) Retry(item T, err error) {
+	if q.backoff.numRequeues(item) >= q.maxRetries {
+		q.Forget(item)
+		q.dead.Send(item, err)
+		return
+	}
+	q.AddRateLimited(item)
+}
+
+// Get blocks until an item is ready (respecting the global token bucket)
+// or the queue is shut down, and marks the returned item as processing.
+// shutdown is true once the queue is shutting down and no items remain.
+func (q *WorkQueue[T]) Get() (item T, shutdown bool) {
+	q.mu.Lock()
+	for len(q.ready) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.ready) == 0 {
+		q.mu.Unlock()
+		var zero T
+		return zero, true
+	}
+
+	item = q.ready[0]
+	q.ready = q.ready[1:]
+	q.processing[item] = true
+	delete(q.dirty, item)
+	q.mu.Unlock()
+
+	_ = q.global.Wait(context.Background()) // never errors: we pass no deadline
+	return item, false
+}
+
+// Done marks item as finished processing. If it was re-Added while it
+// was processing, it is now appended back to the ready FIFO.
+func (q *WorkQueue[T]) Done(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.processing, item)
+	if q.dirty[item] {
+		q.ready = append(q.ready, item)
+		q.cond.Signal()
+	}
+}
+
+// Forget resets an item's backoff state, e.g. after it completes
+// successfully, so its next failure starts at BaseDelay again.
+func (q *WorkQueue[T]) Forget(item T) {
+	q.backoff.forget(item)
+}
+
+// Len reports the number of items currently ready for delivery.
+func (q *WorkQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready)
+}
+
+// ShutDown stops accepting new items and wakes any blocked Get callers.
+func (q *WorkQueue[T]) ShutDown() {
+	q.mu.Lock()
+	q.shuttingDown = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+
+	close(q.stopC)
+	q.dispatched.Wait()
+}
+
+// runDispatcher moves items whose readyAt has elapsed from the delay
+// heap onto the ready FIFO.
+func (q *WorkQueue[T]) runDispatcher() {
+	defer q.dispatched.Done()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		q.mu.Lock()
+		if len(q.waiting) > 0 {
+			resetTimer(timer, time.Until(q.waiting[0].readyAt))
+		} else {
+			resetTimer(timer, time.Hour)
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.stopC:
+			return
+		case <-q.wakeC:
+			continue
+		case <-timer.C:
+			q.releaseDue()
+		}
+	}
+}
+
+func (q *WorkQueue[T]) releaseDue() {
+	now := time.Now()
+	for {
+		q.mu.Lock()
+		if len(q.waiting) == 0 || q.waiting[0].readyAt.After(now) {
+			q.mu.Unlock()
+			return
+		}
+		next := heap.Pop(&q.waiting).(*delayedItem[T])
+		q.mu.Unlock()
+
+		q.Add(next.item)
+	}
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	t.Reset(d)
+}
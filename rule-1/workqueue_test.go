@@ -0,0 +1,113 @@
+package rule_1
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackoffLimiterGrowsAndCaps checks that successive failures double the
+// delay (base*2^failures) up to max, and that Forget resets the sequence.
+func TestBackoffLimiterGrowsAndCaps(t *testing.T) {
+	b := newBackoffLimiter[string](10*time.Millisecond, 100*time.Millisecond)
+
+	if got := b.when("a"); got != 10*time.Millisecond {
+		t.Fatalf("1st delay = %v, want 10ms", got)
+	}
+	if got := b.when("a"); got != 20*time.Millisecond {
+		t.Fatalf("2nd delay = %v, want 20ms", got)
+	}
+	if got := b.when("a"); got != 40*time.Millisecond {
+		t.Fatalf("3rd delay = %v, want 40ms", got)
+	}
+	if got := b.when("a"); got != 80*time.Millisecond {
+		t.Fatalf("4th delay = %v, want 80ms", got)
+	}
+	// 5th would be 160ms, above max, so it's capped.
+	if got := b.when("a"); got != 100*time.Millisecond {
+		t.Fatalf("5th delay = %v, want capped at 100ms", got)
+	}
+
+	if got := b.numRequeues("a"); got != 5 {
+		t.Fatalf("numRequeues = %d, want 5", got)
+	}
+
+	b.forget("a")
+	if got := b.numRequeues("a"); got != 0 {
+		t.Fatalf("numRequeues after forget = %d, want 0", got)
+	}
+	if got := b.when("a"); got != 10*time.Millisecond {
+		t.Fatalf("delay after forget = %v, want back to base 10ms", got)
+	}
+}
+
+// TestWorkQueueAddDedupsWhileProcessing checks that Add while an item is
+// already checked out via Get only marks it dirty for redelivery, rather
+// than appending a second copy to the ready FIFO; the item reappears once
+// Done is called.
+func TestWorkQueueAddDedupsWhileProcessing(t *testing.T) {
+	q := NewWorkQueue[string](WorkQueueConfig[string]{})
+	defer q.ShutDown()
+
+	q.Add("x")
+	item, shutdown := q.Get()
+	if shutdown || item != "x" {
+		t.Fatalf("Get() = (%q, %v), want (\"x\", false)", item, shutdown)
+	}
+
+	// "x" is now processing; Add-ing it again must not duplicate it in
+	// the ready FIFO.
+	q.Add("x")
+	if got := q.Len(); got != 0 {
+		t.Fatalf("Len() while \"x\" is processing = %d, want 0 (redelivery deferred)", got)
+	}
+
+	q.Done("x")
+	if got := q.Len(); got != 1 {
+		t.Fatalf("Len() after Done = %d, want 1 (redelivered)", got)
+	}
+
+	item, shutdown = q.Get()
+	if shutdown || item != "x" {
+		t.Fatalf("redelivered Get() = (%q, %v), want (\"x\", false)", item, shutdown)
+	}
+	q.Done(item)
+}
+
+// TestWorkQueueRetryDeadLettersAfterMaxRetries checks that Retry requeues a
+// failing item up to MaxRetries times and then forgets it and hands it to
+// the DeadLetterSink instead of requeuing it again.
+func TestWorkQueueRetryDeadLettersAfterMaxRetries(t *testing.T) {
+	sink := &recordingSink{}
+	q := NewWorkQueue[string](WorkQueueConfig[string]{
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   time.Millisecond,
+		MaxRetries: 2,
+		DeadLetter: sink,
+	})
+	defer q.ShutDown()
+
+	err := errTransient
+	q.Retry("y", err)
+	q.Retry("y", err)
+	if len(sink.items) != 0 {
+		t.Fatalf("dead-lettered after %d retries, want not yet (MaxRetries=2)", len(sink.items))
+	}
+
+	q.Retry("y", err)
+	if len(sink.items) != 1 || sink.items[0] != "y" {
+		t.Fatalf("sink.items = %v, want [\"y\"] after exceeding MaxRetries", sink.items)
+	}
+	if got := q.backoff.numRequeues("y"); got != 0 {
+		t.Fatalf("numRequeues after dead-letter = %d, want 0 (forgotten)", got)
+	}
+}
+
+// recordingSink is a DeadLetterSink that records every item it receives,
+// for assertions in tests.
+type recordingSink struct {
+	items []string
+}
+
+func (s *recordingSink) Send(item string, err error) {
+	s.items = append(s.items, item)
+}